@@ -0,0 +1,222 @@
+package ingress
+
+import (
+	"container/list"
+	"hash/maphash"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPathCacheShards bounds how many independently locked shards a
+// path cache is split into, so that concurrent hits rarely contend.
+const maxPathCacheShards = 16
+
+// NewCachedIngress returns an Ingress like New that remembers which
+// http.Handler served the maxEntries most recently requested paths.
+// The cache is invalidated whenever Paths changes, whether it is reassigned
+// or its elements are modified in place. Nothing is cached while any of
+// Paths implements RequestMatcher, since matches may then depend on more
+// than the request's path, nor while any of Paths is of a type that is not
+// comparable, since changes to it cannot be detected. A non-positive
+// maxEntries disables caching.
+//
+// The cache is split into shards that are locked independently, and each
+// shard evicts its own least recently used entry, so eviction across the
+// whole cache is only approximately least recently used.
+func NewCachedIngress(maxEntries int, paths ...Path) *Ingress {
+	i := New(paths...)
+
+	if maxEntries > 0 {
+		i.cache = newPathCache(maxEntries)
+	}
+
+	return i
+}
+
+func newPathCache(maxEntries int) *pathCache {
+	var (
+		n = min(maxEntries, maxPathCacheShards)
+		c = &pathCache{
+			seed:   maphash.MakeSeed(),
+			shards: make([]*pathCacheShard, n),
+		}
+	)
+
+	for i := range c.shards {
+		shardEntries := maxEntries / n
+		if i < maxEntries%n {
+			shardEntries++
+		}
+
+		c.shards[i] = &pathCacheShard{
+			maxEntries: shardEntries,
+			entries:    map[string]*list.Element{},
+			order:      list.New(),
+		}
+	}
+
+	return c
+}
+
+// pathsSnapshot is a copy of an Ingress's Paths that the cache's
+// entries were matched against.
+type pathsSnapshot struct {
+	paths     []Path
+	cacheable bool
+}
+
+func newPathsSnapshot(paths []Path) *pathsSnapshot {
+	cacheable := true
+	for _, p := range paths {
+		if _, ok := p.(RequestMatcher); ok {
+			cacheable = false
+			break
+		}
+
+		if t := reflect.TypeOf(p); t != nil && !t.Comparable() {
+			cacheable = false
+			break
+		}
+	}
+
+	return &pathsSnapshot{slices.Clone(paths), cacheable}
+}
+
+// of reports whether paths holds the same Paths as the snapshot.
+func (s *pathsSnapshot) of(paths []Path) bool {
+	if s == nil || len(paths) != len(s.paths) {
+		return false
+	}
+
+	// When the snapshot is cacheable, all of its Paths are of comparable
+	// types, so comparing them with == cannot panic.
+	if s.cacheable {
+		for i, p := range paths {
+			if p != s.paths[i] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for i, p := range paths {
+		if !samePath(p, s.paths[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// samePath compares a and b without panicking when they are of the same
+// type and that type is not comparable, in which case they are not the same.
+func samePath(a, b Path) bool {
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) || (ta != nil && !ta.Comparable()) {
+		return false
+	}
+
+	return a == b
+}
+
+type pathCache struct {
+	mu       sync.Mutex
+	seed     maphash.Seed
+	snapshot atomic.Pointer[pathsSnapshot]
+	shards   []*pathCacheShard
+}
+
+// load returns a snapshot of paths, reusing the current one when paths
+// has not changed since it was taken so that no lock is needed.
+func (c *pathCache) load(paths []Path) *pathsSnapshot {
+	if s := c.snapshot.Load(); s.of(paths) {
+		return s
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.snapshot.Load()
+	if !s.of(paths) {
+		s = newPathsSnapshot(paths)
+		c.snapshot.Store(s)
+	}
+
+	return s
+}
+
+func (c *pathCache) shard(requestPath string) *pathCacheShard {
+	return c.shards[maphash.String(c.seed, requestPath)%uint64(len(c.shards))]
+}
+
+func (c *pathCache) get(s *pathsSnapshot, requestPath string) (http.Handler, bool) {
+	return c.shard(requestPath).get(s, requestPath)
+}
+
+func (c *pathCache) put(s *pathsSnapshot, requestPath string, handler http.Handler) {
+	c.shard(requestPath).put(s, requestPath, handler)
+}
+
+type pathCacheEntry struct {
+	requestPath string
+	handler     http.Handler
+}
+
+type pathCacheShard struct {
+	mu         sync.Mutex
+	maxEntries int
+	snapshot   *pathsSnapshot
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+func (c *pathCacheShard) get(s *pathsSnapshot, requestPath string) (http.Handler, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(s)
+
+	if e, ok := c.entries[requestPath]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*pathCacheEntry).handler, true
+	}
+
+	return nil, false
+}
+
+func (c *pathCacheShard) put(s *pathsSnapshot, requestPath string, handler http.Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(s)
+
+	if e, ok := c.entries[requestPath]; ok {
+		e.Value.(*pathCacheEntry).handler = handler
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[requestPath] = c.order.PushFront(&pathCacheEntry{requestPath, handler})
+
+	if c.order.Len() > c.maxEntries {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.entries, e.Value.(*pathCacheEntry).requestPath)
+	}
+}
+
+// invalidate clears the shard if its entries were not matched against s.
+// It must be called with the lock held.
+func (c *pathCacheShard) invalidate(s *pathsSnapshot) {
+	if c.snapshot == s {
+		return
+	}
+
+	c.snapshot = s
+	clear(c.entries)
+	c.order.Init()
+}
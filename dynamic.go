@@ -0,0 +1,35 @@
+package ingress
+
+import "net/http"
+
+// DynamicPath returns a Path weighed by matcher whose backend is
+// chosen by selector on each request.
+func DynamicPath(selector func(*http.Request) http.Handler, matcher func(string) int) Path {
+	if selector == nil {
+		panic("ingress: nil selector")
+	}
+
+	if matcher == nil {
+		panic("ingress: nil matcher")
+	}
+
+	return &dynamicPath{selector, matcher}
+}
+
+type dynamicPath struct {
+	selector func(*http.Request) http.Handler
+	matcher  func(string) int
+}
+
+func (p *dynamicPath) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if backend := p.selector(r); backend != nil {
+		backend.ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func (p *dynamicPath) Matches(requestPath string) int {
+	return p.matcher(requestPath)
+}
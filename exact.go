@@ -43,6 +43,10 @@ func (p *exactPath) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
+func (p *exactPath) String() string {
+	return p.path
+}
+
 func (p *exactPath) Matches(requestPath string) int {
 	if p.ignoreTrailingSlash {
 		if strings.TrimSuffix(p.path, "/") == strings.TrimSuffix(requestPath, "/") {
@@ -0,0 +1,43 @@
+package ingress
+
+import (
+	"net/http"
+	"path"
+)
+
+// GlobPath returns a Path that matches request paths against the
+// shell glob pattern, as defined by path.Match.
+func GlobPath(pattern string, backend http.Handler) Path {
+	cleaned := normalizePath(pattern)
+	if _, err := path.Match(cleaned, ""); err != nil {
+		panic("ingress: invalid pattern")
+	}
+
+	return &globPath{cleaned, backend}
+}
+
+type globPath struct {
+	pattern string
+	backend http.Handler
+}
+
+func (p *globPath) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.backend != nil {
+		p.backend.ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func (p *globPath) String() string {
+	return p.pattern
+}
+
+func (p *globPath) Matches(requestPath string) int {
+	if matched, _ := path.Match(p.pattern, requestPath); matched {
+		return len(getElements(requestPath)) + 1
+	}
+
+	return 0
+}
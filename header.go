@@ -0,0 +1,34 @@
+package ingress
+
+import "net/http"
+
+// HeaderMatchPath returns a Path that matches requests as fallback does,
+// but only when the request's header equals value. As a RequestMatcher,
+// it takes precedence over a Path that matches the same requests equally
+// strongly without the header, wherever either is declared.
+func HeaderMatchPath(header, value string, fallback Path) Path {
+	return &headerMatchPath{http.CanonicalHeaderKey(header), value, fallback}
+}
+
+type headerMatchPath struct {
+	header   string
+	value    string
+	fallback Path
+}
+
+func (p *headerMatchPath) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.fallback.ServeHTTP(w, r)
+}
+
+// Matches always returns 0, as the request's headers are required to match.
+func (p *headerMatchPath) Matches(_ string) int {
+	return 0
+}
+
+func (p *headerMatchPath) MatchesRequest(r *http.Request, requestPath string) int {
+	if r.Header.Get(p.header) != p.value {
+		return 0
+	}
+
+	return matches(p.fallback, r, requestPath)
+}
@@ -1,35 +1,261 @@
 package ingress
 
-import "net/http"
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+var defaultIngress atomic.Pointer[Ingress]
+
+// Default returns the default Ingress, which serves requests that an
+// Ingress matches no Path for, unless it has a different DefaultBackend
+// than the one New gives it.
+func Default() *Ingress {
+	return defaultIngress.Load()
+}
+
+// SetDefault makes i the default Ingress.
+func SetDefault(i *Ingress) {
+	defaultIngress.Store(i)
+}
 
 type Ingress struct {
+	Router         Router
 	Paths          []Path
 	DefaultBackend http.Handler
+
+	cache *pathCache
 }
 
+// ServeHTTP serves the request with the Path that most strongly matches it.
+// The request's path is cleaned of dot segments and repeated slashes before
+// it is routed, and the Router and the chosen backend see the cleaned path.
 func (i *Ingress) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestPath := normalizePath(r.URL.Path)
+	if requestPath != r.URL.Path {
+		r = withPath(r, requestPath)
+	}
+
+	if i.Router != nil {
+		if h := i.Router.Route(r); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+	}
+
 	var (
-		contender = i.DefaultBackend
-		strongest = 0
+		snapshot  *pathsSnapshot
+		contender http.Handler
+		cached    bool
 	)
 
-	for _, p := range i.Paths {
-		if weight := p.Matches(r.URL.Path); weight > strongest {
-			strongest = weight
-			contender = p
+	if i.cache != nil {
+		if snapshot = i.cache.load(i.Paths); snapshot.cacheable {
+			contender, cached = i.cache.get(snapshot, requestPath)
+		}
+	}
+
+	if !cached {
+		var matched bool
+		contender, matched = i.match(r, requestPath)
+
+		if matched && snapshot != nil && snapshot.cacheable {
+			i.cache.put(snapshot, requestPath, contender)
 		}
 	}
 
 	if contender == nil {
-		contender = http.NotFoundHandler()
+		contender = &defaultBackend{i}
 	}
 
 	contender.ServeHTTP(w, r)
 }
 
+// defaultBackend serves requests with the default Ingress,
+// or responds 404 if there is none or it is self.
+type defaultBackend struct {
+	self *Ingress
+}
+
+func (b *defaultBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if d := Default(); d != nil && d != b.self {
+		d.ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// match returns the Path that most strongly matches the request,
+// or the DefaultBackend and false if none match. Ties go to the earlier
+// Path, unless only the later one is a RequestMatcher.
+func (i *Ingress) match(r *http.Request, requestPath string) (http.Handler, bool) {
+	var (
+		contender          = i.DefaultBackend
+		strongest          = 0
+		strongestIsMatcher = false
+	)
+
+	for _, p := range i.Paths {
+		var (
+			_, isMatcher = p.(RequestMatcher)
+			weight       = matches(p, r, requestPath)
+			breaksTie    = weight > 0 && weight == strongest && isMatcher && !strongestIsMatcher
+		)
+
+		if weight > strongest || breaksTie {
+			strongest = weight
+			strongestIsMatcher = isMatcher
+			contender = p
+		}
+	}
+
+	return contender, strongest > 0
+}
+
+// New returns an Ingress routing to the given paths. Paths that implement
+// fmt.Stringer are sorted among themselves by their string, ignoring any
+// trailing slash, so that routing is deterministic, and a warning is logged
+// for each set of them that match identically. Other Paths keep their
+// declared positions. When Paths tie, the earlier one is matched.
 func New(paths ...Path) *Ingress {
-	return &Ingress{
-		Paths:          paths,
-		DefaultBackend: http.NotFoundHandler(),
+	paths = slices.Clone(paths)
+
+	var (
+		indices  = []int{}
+		stringed = []Path{}
+	)
+
+	for i, p := range paths {
+		if pathString(p) != "" {
+			indices = append(indices, i)
+			stringed = append(stringed, p)
+		}
 	}
+
+	sort.SliceStable(stringed, func(i, j int) bool {
+		return pathKey(stringed[i]) < pathKey(stringed[j])
+	})
+
+	for i, p := range stringed {
+		paths[indices[i]] = p
+	}
+
+	warnDuplicatePaths(stringed)
+
+	i := &Ingress{
+		Paths: paths,
+	}
+	i.DefaultBackend = &defaultBackend{i}
+
+	return i
+}
+
+// withPath returns a shallow copy of r with its URL's path set to requestPath.
+func withPath(r *http.Request, requestPath string) *http.Request {
+	u := *r.URL
+	u.Path = requestPath
+	u.RawPath = ""
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = &u
+
+	return r2
+}
+
+func normalizePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+// warnDuplicatePaths expects paths to be sorted by pathKey, so that only
+// neighbors with the same key need to be compared. It logs one warning
+// for each set of paths that match identically.
+func warnDuplicatePaths(paths []Path) {
+	for start := 0; start < len(paths); {
+		var (
+			key = pathKey(paths[start])
+			end = start + 1
+		)
+
+		for end < len(paths) && pathKey(paths[end]) == key {
+			end++
+		}
+
+		duplicates := [][]Path{}
+		for _, p := range paths[start:end] {
+			found := false
+			for i, d := range duplicates {
+				if matchIdentically(d[0], p) {
+					duplicates[i] = append(d, p)
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				duplicates = append(duplicates, []Path{p})
+			}
+		}
+
+		for _, d := range duplicates {
+			if len(d) > 1 {
+				slog.Warn("ingress: duplicate paths, only the first will be matched", "path", pathString(d[0]), "count", len(d))
+			}
+		}
+
+		start = end
+	}
+}
+
+// matchIdentically reports whether a and b match the same requests with
+// the same weight, judged by the requests for their own strings. A glob's
+// string is a pattern rather than a request path, so globs only match
+// identically when their patterns are equal.
+func matchIdentically(a, b Path) bool {
+	sa, sb := pathString(a), pathString(b)
+
+	_, aGlob := a.(*globPath)
+	_, bGlob := b.(*globPath)
+	if aGlob || bGlob {
+		return aGlob && bGlob && sa == sb
+	}
+
+	weight := a.Matches(sa)
+
+	return weight > 0 && weight == b.Matches(sa) && a.Matches(sb) == b.Matches(sb)
+}
+
+// pathKey is the string that paths are sorted and grouped by. It ignores
+// a trailing slash so that paths which differ only by one are neighbors.
+func pathKey(p Path) string {
+	s := pathString(p)
+	if s != "/" {
+		s = strings.TrimSuffix(s, "/")
+	}
+
+	return s
+}
+
+func pathString(p Path) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return ""
 }
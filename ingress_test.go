@@ -1,10 +1,16 @@
 package ingress_test
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/frantjc/go-ingress"
@@ -88,3 +94,710 @@ func TestIngress(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultIngress(t *testing.T) {
+	defaultBody := uuid.NewString()
+
+	ingress.SetDefault(ingress.New(
+		ingress.PrefixPath(
+			"/",
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(defaultBody))
+			}),
+		),
+	))
+	t.Cleanup(func() {
+		ingress.SetDefault(nil)
+	})
+
+	i := ingress.New(
+		ingress.ExactPath("/exact", nil),
+	)
+
+	if i.DefaultBackend == nil {
+		t.Error("expected New to set a DefaultBackend")
+		t.FailNow()
+	}
+
+	for _, m := range []struct {
+		path, expected string
+	}{
+		{"/notfound", defaultBody},
+		{"/exact", "404 page not found\n"},
+	} {
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, m.path, nil))
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "from path", m.path, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+	}
+
+	// An explicit DefaultBackend takes precedence over the default Ingress.
+	i.DefaultBackend = http.NotFoundHandler()
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notfound", nil))
+
+	if actual := w.Body.String(); actual != "404 page not found\n" {
+		t.Error("actual", actual, "does not equal expected 404 page not found")
+		t.FailNow()
+	}
+}
+
+func TestNewDuplicatePaths(t *testing.T) {
+	var (
+		buf           = new(bytes.Buffer)
+		defaultLogger = slog.Default()
+		firstBody     = uuid.NewString()
+		duplicateBody = uuid.NewString()
+	)
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(buf, nil)))
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+	})
+
+	paths := []ingress.Path{
+		ingress.PrefixPath("/b", nil),
+		ingress.DynamicPath(
+			func(*http.Request) http.Handler { return nil },
+			func(string) int { return 0 },
+		),
+		ingress.PrefixPath(
+			"/a",
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(firstBody))
+			}),
+		),
+		ingress.PrefixPath(
+			"/a/",
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(duplicateBody))
+			}),
+		),
+	}
+
+	i := ingress.New(paths...)
+
+	if warnings := strings.Count(buf.String(), "duplicate paths"); warnings != 1 {
+		t.Error("expected exactly 1 duplicate paths warning, got", buf.String())
+		t.FailNow()
+	}
+
+	if actual := paths[0].(fmt.Stringer).String(); actual != "/b" {
+		t.Error("New reordered the given paths, actual", actual, "at index 0 does not equal expected /b")
+		t.FailNow()
+	}
+
+	if _, ok := i.Paths[1].(fmt.Stringer); ok {
+		t.Error("expected Path without String to keep its declared position")
+		t.FailNow()
+	}
+
+	for j, expected := range map[int]string{0: "/a", 2: "/a", 3: "/b"} {
+		if actual := i.Paths[j].(fmt.Stringer).String(); actual != expected {
+			t.Error("actual", actual, "at index", j, "does not equal expected", expected)
+			t.FailNow()
+		}
+	}
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/a/c", nil))
+
+	if actual := w.Body.String(); actual != firstBody {
+		t.Error("actual", actual, "does not equal expected", firstBody)
+		t.FailNow()
+	}
+}
+
+func TestNewDuplicatePathsGrouped(t *testing.T) {
+	var (
+		buf           = new(bytes.Buffer)
+		defaultLogger = slog.Default()
+	)
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(buf, nil)))
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+	})
+
+	ingress.New(
+		ingress.PrefixPath("/a", nil),
+		ingress.PrefixPath("/a", nil),
+		ingress.PrefixPath("/a", nil),
+		ingress.ExactPath("/b", nil, ingress.WithMatchIgnoreSlash),
+		ingress.ExactPath("/b-c", nil),
+		ingress.ExactPath("/b/", nil, ingress.WithMatchIgnoreSlash),
+		ingress.ExactPath("/c", nil),
+		ingress.ExactPath("/c/", nil),
+	)
+
+	for _, expected := range []string{"path=/a count=3", "path=/b count=2"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error("expected a duplicate paths warning with", expected, "got", buf.String())
+			t.FailNow()
+		}
+	}
+
+	if warnings := strings.Count(buf.String(), "duplicate paths"); warnings != 2 {
+		t.Error("expected exactly 2 duplicate paths warnings, got", buf.String())
+		t.FailNow()
+	}
+}
+
+func TestGlobPath(t *testing.T) {
+	var (
+		globBody   = uuid.NewString()
+		prefixBody = uuid.NewString()
+		i          = ingress.New(
+			ingress.GlobPath(
+				"/api/*/resource",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(globBody))
+				}),
+			),
+			ingress.PrefixPath(
+				"/api",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(prefixBody))
+				}),
+			),
+		)
+	)
+
+	for _, m := range []struct {
+		path, expected string
+	}{
+		{"/api/v1/resource", globBody},
+		{"/api/v2/resource", globBody},
+		{"/api/v1/v2/resource", prefixBody},
+		{"/api/v1/other", prefixBody},
+		{"/other/v1/resource", "404 page not found\n"},
+		{"/api/v1/resource/", prefixBody},
+	} {
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, m.path, nil))
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "from path", m.path, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+	}
+}
+
+func TestGlobPathTrailingSlash(t *testing.T) {
+	var (
+		globBody = uuid.NewString()
+		i        = ingress.New(
+			ingress.GlobPath(
+				"/api/*/",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(globBody))
+				}),
+			),
+		)
+	)
+
+	for _, m := range []struct {
+		path, expected string
+	}{
+		{"/api/v1/", globBody},
+		{"/api/v1", "404 page not found\n"},
+	} {
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, m.path, nil))
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "from path", m.path, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+	}
+}
+
+func TestGlobPathWeight(t *testing.T) {
+	var (
+		globBody   = uuid.NewString()
+		rootBody   = uuid.NewString()
+		prefixBody = uuid.NewString()
+		i          = ingress.New(
+			ingress.PrefixPath(
+				"/api",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(prefixBody))
+				}),
+			),
+			ingress.GlobPath(
+				"/api/*",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(globBody))
+				}),
+			),
+			ingress.GlobPath(
+				"/*",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(rootBody))
+				}),
+			),
+		)
+	)
+
+	for _, m := range []struct {
+		path, expected string
+	}{
+		{"/", rootBody},
+		{"/api/v1", globBody},
+		{"/api/v1/v2", prefixBody},
+	} {
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, m.path, nil))
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "from path", m.path, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+	}
+
+	w := httptest.NewRecorder()
+	ingress.New(ingress.GlobPath("/", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(rootBody))
+	}))).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if actual := w.Body.String(); actual != rootBody {
+		t.Error("actual", actual, "from path / does not equal expected", rootBody)
+		t.FailNow()
+	}
+}
+
+func TestGlobPathDuplicates(t *testing.T) {
+	var (
+		buf           = new(bytes.Buffer)
+		defaultLogger = slog.Default()
+	)
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(buf, nil)))
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+	})
+
+	ingress.New(
+		ingress.GlobPath("/a/*", nil),
+		ingress.GlobPath("/a/?", nil),
+		ingress.GlobPath("/b/[bc]", nil),
+		ingress.GlobPath("/b/[bc]", nil),
+	)
+
+	if warnings := strings.Count(buf.String(), "duplicate paths"); warnings != 1 {
+		t.Error("expected exactly 1 duplicate paths warning, got", buf.String())
+		t.FailNow()
+	}
+}
+
+func TestHeaderMatchPath(t *testing.T) {
+	var (
+		v1Body = uuid.NewString()
+		v2Body = uuid.NewString()
+		v1     = ingress.PrefixPath(
+			"/",
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(v1Body))
+			}),
+		)
+		v2 = ingress.HeaderMatchPath(
+			"X-Feature-Flag", "v2",
+			ingress.PrefixPath(
+				"/",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(v2Body))
+				}),
+			),
+		)
+	)
+
+	// The HeaderMatchPath takes precedence whether it is declared before or after.
+	for _, i := range []*ingress.Ingress{
+		ingress.New(v2, v1),
+		ingress.New(v1, v2),
+	} {
+		for _, m := range []struct {
+			header, expected string
+		}{
+			{"", v1Body},
+			{"v1", v1Body},
+			{"v2", v2Body},
+		} {
+			r := httptest.NewRequest(http.MethodGet, "/path", nil)
+			if m.header != "" {
+				r.Header.Set("X-Feature-Flag", m.header)
+			}
+
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, r)
+
+			if actual := w.Body.String(); actual != m.expected {
+				t.Error("actual", actual, "from header", m.header, "does not equal expected", m.expected)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var (
+		routerBody = uuid.NewString()
+		exactBody  = uuid.NewString()
+		i          = ingress.New(
+			ingress.ExactPath(
+				"/exact",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(exactBody))
+				}),
+			),
+		)
+	)
+
+	i.Router = ingress.RouterFunc(func(r *http.Request) http.Handler {
+		if r.Header.Get("X-Override") == "" {
+			return nil
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(routerBody))
+		})
+	})
+
+	for _, m := range []struct {
+		override bool
+		expected string
+	}{
+		{false, exactBody},
+		{true, routerBody},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/exact", nil)
+		if m.override {
+			r.Header.Set("X-Override", "true")
+		}
+
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, r)
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+	}
+}
+
+func TestDynamicPath(t *testing.T) {
+	var (
+		tenants = map[string]string{
+			"a": uuid.NewString(),
+			"b": uuid.NewString(),
+		}
+		i = ingress.New(
+			ingress.DynamicPath(
+				func(r *http.Request) http.Handler {
+					body, ok := tenants[r.Header.Get("X-Tenant")]
+					if !ok {
+						return nil
+					}
+
+					return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						w.Write([]byte(body))
+					})
+				},
+				ingress.PrefixPath("/tenant", nil).Matches,
+			),
+		)
+	)
+
+	for _, m := range []struct {
+		path, tenant, expected string
+	}{
+		{"/tenant", "a", tenants["a"]},
+		{"/tenant/path", "b", tenants["b"]},
+		{"/tenant", "c", "404 page not found\n"},
+		{"/other", "a", "404 page not found\n"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, m.path, nil)
+		r.Header.Set("X-Tenant", m.tenant)
+
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, r)
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "from path", m.path, "and tenant", m.tenant, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+	}
+}
+
+func TestDynamicPathNil(t *testing.T) {
+	for _, m := range []struct {
+		selector func(*http.Request) http.Handler
+		matcher  func(string) int
+		expected string
+	}{
+		{nil, func(string) int { return 0 }, "ingress: nil selector"},
+		{func(*http.Request) http.Handler { return nil }, nil, "ingress: nil matcher"},
+	} {
+		func() {
+			defer func() {
+				if actual := recover(); actual != m.expected {
+					t.Error("actual panic", actual, "does not equal expected", m.expected)
+					t.FailNow()
+				}
+			}()
+
+			ingress.DynamicPath(m.selector, m.matcher)
+		}()
+	}
+}
+
+func TestIngressNormalizesPath(t *testing.T) {
+	var (
+		exactBody  = uuid.NewString()
+		prefixBody = uuid.NewString()
+		i          = ingress.New(
+			ingress.ExactPath(
+				"/admin",
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(exactBody + r.URL.Path))
+				}),
+			),
+			ingress.PrefixPath(
+				"/api",
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(prefixBody + r.URL.Path))
+				}),
+			),
+		)
+	)
+
+	for _, m := range []struct {
+		path, expected string
+	}{
+		{"/api/../admin", exactBody + "/admin"},
+		{"/api/./v1", prefixBody + "/api/v1"},
+		{"/./admin", exactBody + "/admin"},
+		{"//admin", exactBody + "/admin"},
+		{"//api//v1", prefixBody + "/api/v1"},
+		{"/admin/", "404 page not found\n"},
+		{"/admin/.", exactBody + "/admin"},
+		{"/api/..", "404 page not found\n"},
+		{"/../api", prefixBody + "/api"},
+		{"/internal/../api/x/", prefixBody + "/api/x/"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = m.path
+		r.URL.RawPath = m.path
+
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, r)
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "from path", m.path, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+
+		if r.URL.Path != m.path {
+			t.Error("ServeHTTP modified the given request's path", m.path, "to", r.URL.Path)
+			t.FailNow()
+		}
+	}
+}
+
+// countingPath counts how many times its Path's Matches is called.
+type countingPath struct {
+	ingress.Path
+	calls *atomic.Int32
+}
+
+func (p countingPath) Matches(requestPath string) int {
+	p.calls.Add(1)
+	return p.Path.Matches(requestPath)
+}
+
+func TestCachedIngress(t *testing.T) {
+	var (
+		bodies = []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+		calls  = new(atomic.Int32)
+		prefix = func(body string) ingress.Path {
+			return countingPath{
+				ingress.PrefixPath(
+					"/",
+					http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						w.Write([]byte(body))
+					}),
+				),
+				calls,
+			}
+		}
+		i     = ingress.NewCachedIngress(1, prefix(bodies[0]))
+		serve = func(path, expected string, expectedCalls int32) {
+			t.Helper()
+
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if actual := w.Body.String(); actual != expected {
+				t.Error("actual", actual, "from path", path, "does not equal expected", expected)
+				t.FailNow()
+			}
+
+			if actual := calls.Load(); actual != expectedCalls {
+				t.Error("actual", actual, "calls to Matches after path", path, "does not equal expected", expectedCalls)
+				t.FailNow()
+			}
+		}
+	)
+
+	// A hit is served without matching again.
+	serve("/a", bodies[0], 1)
+	serve("/a", bodies[0], 1)
+
+	// With maxEntries 1, serving /b evicts /a, so /a is matched again.
+	serve("/b", bodies[0], 2)
+	serve("/a", bodies[0], 3)
+
+	// Modifying Paths in place invalidates the cache.
+	i.Paths[0] = prefix(bodies[1])
+	serve("/a", bodies[1], 4)
+
+	i.Paths = append(i.Paths[:0], prefix(bodies[2]))
+	serve("/a", bodies[2], 5)
+
+	// So does reassigning Paths.
+	i.Paths = []ingress.Path{prefix(bodies[0])}
+	serve("/a", bodies[0], 6)
+}
+
+func TestCachedIngressRequestMatcher(t *testing.T) {
+	var (
+		v1Body = uuid.NewString()
+		v2Body = uuid.NewString()
+		i      = ingress.NewCachedIngress(
+			10,
+			ingress.HeaderMatchPath(
+				"X-Feature-Flag", "v2",
+				ingress.PrefixPath(
+					"/",
+					http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						w.Write([]byte(v2Body))
+					}),
+				),
+			),
+			ingress.PrefixPath(
+				"/",
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Write([]byte(v1Body))
+				}),
+			),
+		)
+	)
+
+	for _, m := range []struct {
+		header, expected string
+	}{
+		{"", v1Body},
+		{"v2", v2Body},
+		{"", v1Body},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/path", nil)
+		if m.header != "" {
+			r.Header.Set("X-Feature-Flag", m.header)
+		}
+
+		w := httptest.NewRecorder()
+		i.ServeHTTP(w, r)
+
+		if actual := w.Body.String(); actual != m.expected {
+			t.Error("actual", actual, "from header", m.header, "does not equal expected", m.expected)
+			t.FailNow()
+		}
+	}
+}
+
+func benchmarkIngress(b *testing.B, i *ingress.Ingress, path string) {
+	b.Helper()
+	b.ReportAllocs()
+
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		i.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+func BenchmarkIngress_ExactPath(b *testing.B) {
+	benchmarkIngress(b, ingress.New(ingress.ExactPath("/exact", http.NotFoundHandler())), "/exact")
+}
+
+func BenchmarkIngress_PrefixPath(b *testing.B) {
+	benchmarkIngress(b, ingress.New(ingress.PrefixPath("/prefix", http.NotFoundHandler())), "/prefix/path")
+}
+
+func BenchmarkIngress_ManyPaths(b *testing.B) {
+	benchmarkManyPaths(b, ingress.New)
+}
+
+func BenchmarkCachedIngress_ManyPaths(b *testing.B) {
+	benchmarkManyPaths(b, func(paths ...ingress.Path) *ingress.Ingress {
+		return ingress.NewCachedIngress(len(paths), paths...)
+	})
+}
+
+func BenchmarkIngress_ManyPathsParallel(b *testing.B) {
+	benchmarkManyPathsParallel(b, ingress.New)
+}
+
+func BenchmarkCachedIngress_ManyPathsParallel(b *testing.B) {
+	benchmarkManyPathsParallel(b, func(paths ...ingress.Path) *ingress.Ingress {
+		return ingress.NewCachedIngress(len(paths), paths...)
+	})
+}
+
+func benchmarkManyPathsParallel(b *testing.B, newIngress func(...ingress.Path) *ingress.Ingress) {
+	b.Helper()
+
+	const n = 1000
+
+	paths := make([]ingress.Path, n)
+	for j := range paths {
+		paths[j] = ingress.PrefixPath(fmt.Sprintf("/prefix/%d", j), http.NotFoundHandler())
+	}
+
+	i := newIngress(paths...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		rs := make([]*http.Request, 100)
+		for j := range rs {
+			rs[j] = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/prefix/%d/path", j*n/len(rs)), nil)
+		}
+
+		for j := 0; pb.Next(); j++ {
+			i.ServeHTTP(httptest.NewRecorder(), rs[j%len(rs)])
+		}
+	})
+}
+
+func benchmarkManyPaths(b *testing.B, newIngress func(...ingress.Path) *ingress.Ingress) {
+	b.Helper()
+
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			paths := make([]ingress.Path, n)
+			for j := range paths {
+				paths[j] = ingress.PrefixPath(fmt.Sprintf("/prefix/%d", j), http.NotFoundHandler())
+			}
+
+			benchmarkIngress(b, newIngress(paths...), fmt.Sprintf("/prefix/%d/path", n-1))
+		})
+	}
+}
@@ -8,3 +8,19 @@ type Path interface {
 	// how strong of a match this path is to the request. <0 is infinity.
 	Matches(string) int
 }
+
+// RequestMatcher may be implemented by a Path that needs more than the
+// request's path to determine its weight. When implemented, MatchesRequest
+// is used in place of Matches and is given the request along with its
+// cleaned path. A RequestMatcher wins ties with Paths that are not one.
+type RequestMatcher interface {
+	MatchesRequest(r *http.Request, requestPath string) int
+}
+
+func matches(p Path, r *http.Request, requestPath string) int {
+	if m, ok := p.(RequestMatcher); ok {
+		return m.MatchesRequest(r, requestPath)
+	}
+
+	return p.Matches(requestPath)
+}
@@ -29,6 +29,10 @@ func (p *prefixPath) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
+func (p *prefixPath) String() string {
+	return "/" + strings.Join(p.elements, "/")
+}
+
 func (p *prefixPath) Matches(requestPath string) int {
 	elements := getElements(requestPath)
 
@@ -0,0 +1,16 @@
+package ingress
+
+import "net/http"
+
+// Router is consulted by an Ingress before its Paths. Route returns
+// the http.Handler to serve the request or nil to fall through.
+type Router interface {
+	Route(*http.Request) http.Handler
+}
+
+// RouterFunc is an adapter to allow the use of ordinary functions as Routers.
+type RouterFunc func(*http.Request) http.Handler
+
+func (f RouterFunc) Route(r *http.Request) http.Handler {
+	return f(r)
+}